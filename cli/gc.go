@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"fmt"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/knoebber/dotfile/local"
+)
+
+type gcCommand struct{}
+
+func (gc *gcCommand) run(*kingpin.ParseContext) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+
+	removed, err := local.GC(local.DefaultDir(home))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("removed %d unreferenced object(s)\n", removed)
+	return nil
+}
+
+func addGCSubCommandToApplication(app *kingpin.Application) {
+	gc := new(gcCommand)
+
+	app.Command("gc", "delete object store blobs that no tracked file references").Action(gc.run)
+}
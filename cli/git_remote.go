@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"time"
+
+	"github.com/knoebber/dotfile/file"
+	"github.com/knoebber/dotfile/gitstorer"
+	"github.com/knoebber/dotfile/local"
+)
+
+// gitCacheDir is where gitstorer keeps its local clones, one per alias.
+func gitCacheDir(s *local.Storage) string {
+	return filepath.Join(s.Home, ".cache", "dotfile", "git-remotes")
+}
+
+// openGitStorer opens a gitstorer.Storage for s's alias against remoteURL.
+func openGitStorer(s *local.Storage, remoteURL string) (*gitstorer.Storage, error) {
+	gs := &gitstorer.Storage{
+		RemoteURL: remoteURL,
+		CacheDir:  gitCacheDir(s),
+		Alias:     s.Alias,
+		Path:      gitstorer.NormalizeTreePath(s.FileData.Path),
+	}
+
+	if err := gs.Open(); err != nil {
+		return nil, err
+	}
+
+	return gs, nil
+}
+
+// pushGit copies s's current, on-disk contents into the clone, commits
+// them to the alias' branch in remoteURL when s's current revision isn't
+// on that branch yet, and pushes it - an alternative to pushing to a
+// dotfilehub server. When the revision is already there nothing is
+// committed, even if the on-disk file has changed since that revision.
+func pushGit(s *local.Storage, remoteURL string) error {
+	gs, err := openGitStorer(s, remoteURL)
+	if err != nil {
+		return err
+	}
+
+	contents, err := s.GetContents()
+	if err != nil {
+		return err
+	}
+
+	// gs.GetContents only ever reads from the clone, so the real file's
+	// current contents have to be copied in before file.NewCommit hashes
+	// "the current state" - otherwise a brand new clone has nothing at
+	// Path at all, and an existing one just re-hashes whatever was last
+	// checked out.
+	if err := gs.WriteWorkingFile(contents); err != nil {
+		return err
+	}
+
+	exists, err := gs.HasCommit(s.FileData.Revision)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := file.NewCommit(gs, "push from local"); err != nil {
+			return err
+		}
+	}
+
+	return gs.Push()
+}
+
+// pullGit fetches the alias' branch from remoteURL, records its latest
+// revision when the local JSON doesn't already know about it, and checks
+// that revision out - mirroring what local.Storage.Pull does for a
+// dotfilehub remote.
+func pullGit(s *local.Storage, remoteURL string) error {
+	gs, err := openGitStorer(s, remoteURL)
+	if err != nil {
+		return err
+	}
+
+	if err := gs.Pull(); err != nil {
+		return err
+	}
+
+	hash, message, err := gs.LatestCommit()
+	if err != nil {
+		return err
+	}
+
+	alreadyTracked, err := s.HasCommit(hash)
+	if err != nil {
+		return err
+	}
+
+	if !alreadyTracked {
+		compressed, err := gs.GetRevision(hash)
+		if err != nil {
+			return err
+		}
+
+		c := &file.Commit{
+			Hash:      hash,
+			Message:   message,
+			Timestamp: time.Now().Unix(),
+		}
+
+		if err := s.SaveCommit(bytes.NewBuffer(compressed), c); err != nil {
+			return err
+		}
+	}
+
+	// Checks out hash and closes storage, same as local.Storage.Pull.
+	return file.Checkout(s, hash)
+}
@@ -0,0 +1,29 @@
+package cli
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+type pullCommand struct {
+	alias     string
+	remoteGit string
+}
+
+func (pc *pullCommand) run(*kingpin.ParseContext) error {
+	s, err := loadFile(pc.alias)
+	if err != nil {
+		return err
+	}
+
+	if pc.remoteGit != "" {
+		return pullGit(s, pc.remoteGit)
+	}
+
+	return s.Pull()
+}
+
+func addPullSubCommandToApplication(app *kingpin.Application) {
+	pc := new(pullCommand)
+
+	p := app.Command("pull", "pull a file's commits from a remote").Action(pc.run)
+	p.Arg("alias", "the file to pull").Required().StringVar(&pc.alias)
+	p.Flag("remote-git", "pull from a git remote instead of a dotfile server, e.g. git@github.com:me/dotfiles.git").StringVar(&pc.remoteGit)
+}
@@ -0,0 +1,29 @@
+package cli
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+type pushCommand struct {
+	alias     string
+	remoteGit string
+}
+
+func (pc *pushCommand) run(*kingpin.ParseContext) error {
+	s, err := loadFile(pc.alias)
+	if err != nil {
+		return err
+	}
+
+	if pc.remoteGit != "" {
+		return pushGit(s, pc.remoteGit)
+	}
+
+	return s.Push()
+}
+
+func addPushSubCommandToApplication(app *kingpin.Application) {
+	pc := new(pushCommand)
+
+	p := app.Command("push", "push a file's commits to a remote").Action(pc.run)
+	p.Arg("alias", "the file to push").Required().StringVar(&pc.alias)
+	p.Flag("remote-git", "push to a git remote instead of a dotfile server, e.g. git@github.com:me/dotfiles.git").StringVar(&pc.remoteGit)
+}
@@ -0,0 +1,375 @@
+// Package gitstorer implements file.Storer on top of a plain git remote.
+//
+// Unlike local.Storage, which keeps its own JSON history and blob files,
+// gitstorer keeps a single local clone per alias under Storage.CacheDir
+// and treats that clone as the source of truth: every tracked
+// alias lives on its own branch, and every dotfile commit becomes a real
+// git commit whose tree contains the file at its declared path. Push and
+// Pull are backed by go-git's transport, so any host that speaks git over
+// ssh or https - Gitea, GitHub, a bare repo on a NAS - works as a dotfile
+// remote without running dotfilehub.
+//
+// A dotfile commit's hash is the content hash computed by
+// file.hashAndCompress, not a git commit SHA, so it can't be looked up
+// with repo.CommitObject directly. Instead each commit's message carries
+// a trailer line of the form "Dotfile-Hash: <hash>", and HasCommit/
+// GetRevision walk the branch's history looking for it.
+package gitstorer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gittransport "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/pkg/errors"
+
+	"github.com/knoebber/dotfile/file"
+)
+
+const (
+	hashTrailerPrefix = "Dotfile-Hash: "
+	branchPrefix      = "dotfile/"
+	originRemote      = "origin"
+)
+
+// Storage implements file.Storer against a remote git repository.
+// Each tracked alias is stored as a branch named "dotfile/<alias>"; the
+// file itself lives at Path within that branch's tree.
+type Storage struct {
+	RemoteURL string // git@host:user/repo.git or https://host/user/repo.git
+	CacheDir  string // directory that holds the local clones, one per alias
+	Alias     string // the dotfile alias, used to name the branch
+	Path      string // the file's path within the repo tree
+
+	dir  string // s.CacheDir/s.Alias, the local clone
+	repo *git.Repository
+	wt   *git.Worktree
+}
+
+// NormalizeTreePath turns a tracked file's declared path - which may be
+// "~"-relative or absolute, e.g. "~/.bashrc" or "/etc/hosts" - into a
+// path that's safe to use inside a git tree: relative, with no leading
+// "~" or "/". "~/.bashrc" becomes ".bashrc"; "/etc/hosts" becomes
+// "etc/hosts".
+func NormalizeTreePath(path string) string {
+	path = strings.TrimPrefix(path, "~")
+	return strings.TrimPrefix(path, "/")
+}
+
+// branchName is the name of the branch that stores this alias' history.
+func (s *Storage) branchName() string {
+	return branchPrefix + s.Alias
+}
+
+func (s *Storage) branchRef() plumbing.ReferenceName {
+	return plumbing.NewBranchReferenceName(s.branchName())
+}
+
+// Open clones the remote into s.CacheDir/s.Alias if it isn't already
+// present, fetches, and checks out - creating, if necessary - the branch
+// for s.Alias. It must be called before any other method.
+func (s *Storage) Open() error {
+	if s.RemoteURL == "" {
+		return errors.New("remote url not set")
+	}
+	if s.CacheDir == "" {
+		return errors.New("cache dir not set")
+	}
+	if s.Alias == "" {
+		return errors.New("alias not set")
+	}
+
+	s.dir = filepath.Join(s.CacheDir, s.Alias)
+
+	repo, err := git.PlainOpen(s.dir)
+	if err == git.ErrRepositoryNotExists {
+		repo, err = git.PlainClone(s.dir, false, &git.CloneOptions{
+			URL:  s.RemoteURL,
+			Auth: s.auth(),
+		})
+	}
+	if err != nil {
+		return errors.Wrapf(err, "opening git remote %q", s.RemoteURL)
+	}
+	s.repo = repo
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return errors.Wrap(err, "getting worktree")
+	}
+	s.wt = wt
+
+	if err := s.fetch(); err != nil {
+		return err
+	}
+
+	return s.checkoutBranch()
+}
+
+// auth picks go-git transport auth from the environment, mirroring how
+// a plain `git push`/`git pull` would: ssh keys for ssh:// and git@
+// remotes, nothing extra for https (only a user:token embedded in
+// RemoteURL is used; git credential helpers are not consulted).
+func (s *Storage) auth() transport.AuthMethod {
+	if strings.HasPrefix(s.RemoteURL, "http") {
+		return nil
+	}
+
+	auth, err := gitssh.NewSSHAgentAuth("git")
+	if err != nil {
+		return nil
+	}
+	return auth
+}
+
+func (s *Storage) fetch() error {
+	err := s.repo.Fetch(&git.FetchOptions{
+		RemoteName: originRemote,
+		Auth:       s.auth(),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return errors.Wrap(err, "fetching remote")
+	}
+	return nil
+}
+
+// checkoutBranch checks out s.branchRef, creating it at the commit HEAD
+// currently points to when no local branch of that name exists yet.
+func (s *Storage) checkoutBranch() error {
+	err := s.wt.Checkout(&git.CheckoutOptions{
+		Branch: s.branchRef(),
+	})
+	if err == nil {
+		return nil
+	}
+
+	return s.wt.Checkout(&git.CheckoutOptions{
+		Branch: s.branchRef(),
+		Create: true,
+	})
+}
+
+// Close flushes nothing; go-git has no handles that need releasing.
+func (s *Storage) Close() error {
+	return nil
+}
+
+// HasCommit returns whether a commit carrying the Dotfile-Hash trailer
+// for hash exists on this alias' branch.
+func (s *Storage) HasCommit(hash string) (bool, error) {
+	_, err := s.findCommit(hash)
+	if err == object.ErrCommitNotFound || err == errHashNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var errHashNotFound = errors.New("hash not found in branch history")
+
+// LatestCommit returns the Dotfile-Hash and message of this alias'
+// branch's most recent commit. The CLI uses it after Pull to learn what
+// the remote's newest revision is, the same way a dotfilehub pull
+// learns it from the server's response.
+func (s *Storage) LatestCommit() (hash, message string, err error) {
+	head, err := s.repo.Reference(s.branchRef(), true)
+	if err != nil {
+		return "", "", errors.Wrap(err, "resolving branch head")
+	}
+
+	commit, err := s.repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", "", errors.Wrap(err, "reading latest commit")
+	}
+
+	idx := strings.Index(commit.Message, hashTrailerPrefix)
+	if idx == -1 {
+		return "", "", errors.Errorf("latest commit %s has no %s trailer", head.Hash(), hashTrailerPrefix)
+	}
+
+	message = strings.TrimSpace(commit.Message[:idx])
+	hash = strings.TrimSpace(commit.Message[idx+len(hashTrailerPrefix):])
+
+	return hash, message, nil
+}
+
+// findCommit walks the branch's history looking for the commit whose
+// message carries hash's trailer.
+func (s *Storage) findCommit(hash string) (*object.Commit, error) {
+	head, err := s.repo.Reference(s.branchRef(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	commitIter, err := s.repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, err
+	}
+
+	var found *object.Commit
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		if strings.Contains(c.Message, hashTrailerPrefix+hash) {
+			found = c
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, errHashNotFound
+	}
+
+	return found, nil
+}
+
+// GetRevision returns the compressed contents of the blob at Path in
+// the commit tagged with hash. The tree itself holds the plaintext file
+// - that's the point, so `git show`/`git diff` on this branch are
+// readable - so it's recompressed here to satisfy the same contract
+// every other Storer.GetRevision follows: callers like
+// file.UncompressRevision always uncompress what comes back.
+func (s *Storage) GetRevision(hash string) ([]byte, error) {
+	commit, err := s.findCommit(hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "finding revision %#v", hash)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := tree.File(s.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %q at revision %#v", s.Path, hash)
+	}
+
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := file.Compress([]byte(contents))
+	if err != nil {
+		return nil, errors.Wrapf(err, "compressing revision %#v", hash)
+	}
+
+	return compressed.Bytes(), nil
+}
+
+// GetContents reads the file at Path out of the current worktree.
+func (s *Storage) GetContents() ([]byte, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(s.dir, s.Path))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading file contents")
+	}
+
+	return contents, nil
+}
+
+// WriteWorkingFile overwrites Path within the clone's worktree, without
+// staging or committing anything. GetContents only ever reads from this
+// clone, so a caller that wants to commit the real tracked file's
+// current, on-disk contents - not whatever the clone last had checked
+// out - must write them here first.
+func (s *Storage) WriteWorkingFile(contents []byte) error {
+	fullPath := filepath.Join(s.dir, s.Path)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return errors.Wrap(err, "creating directories for working file")
+	}
+
+	return ioutil.WriteFile(fullPath, contents, 0644)
+}
+
+// SaveCommit writes buff to Path in the worktree and creates a commit on
+// this alias' branch carrying c's message and a Dotfile-Hash trailer.
+// buff holds file.NewCommit's compressed bytes, but the whole point of
+// gitstorer is a tree that `git show`/`git diff` can read, so it's
+// uncompressed before being written - GetRevision recompresses on the
+// way back out.
+func (s *Storage) SaveCommit(buff *bytes.Buffer, c *file.Commit) error {
+	uncompressed, err := file.Uncompress(buff.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "uncompressing commit")
+	}
+
+	if err := s.WriteWorkingFile(uncompressed.Bytes()); err != nil {
+		return err
+	}
+
+	if _, err := s.wt.Add(s.Path); err != nil {
+		return errors.Wrap(err, "staging commit")
+	}
+
+	message := fmt.Sprintf("%s\n\n%s%s", c.Message, hashTrailerPrefix, c.Hash)
+	if _, err := s.wt.Commit(message, &git.CommitOptions{}); err != nil {
+		return errors.Wrap(err, "creating commit")
+	}
+
+	return nil
+}
+
+// Revert overwrites Path in the worktree with buff, mirroring
+// local.Storage.Revert. Nothing is checked out, staged or committed.
+func (s *Storage) Revert(buff *bytes.Buffer, hash string) error {
+	if err := s.WriteWorkingFile(buff.Bytes()); err != nil {
+		return errors.Wrapf(err, "reverting file %q", s.Path)
+	}
+
+	return nil
+}
+
+// Push pushes this alias' branch to the remote.
+func (s *Storage) Push() error {
+	err := s.repo.Push(&git.PushOptions{
+		RemoteName: originRemote,
+		Auth:       s.auth(),
+		RefSpecs: []gitconfig.RefSpec{
+			gitconfig.RefSpec(s.branchRef() + ":" + s.branchRef()),
+		},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return errors.Wrap(err, "pushing to remote")
+	}
+
+	return nil
+}
+
+// Pull fetches this alias' branch from the remote and fast-forwards the
+// worktree to it.
+func (s *Storage) Pull() error {
+	if err := s.fetch(); err != nil {
+		return err
+	}
+
+	err := s.wt.Pull(&git.PullOptions{
+		RemoteName:    originRemote,
+		ReferenceName: s.branchRef(),
+		Auth:          s.auth(),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return errors.Wrap(err, "pulling from remote")
+	}
+
+	return nil
+}
+
+// ensure the http transport package is linked in so https remotes work
+// without the caller needing a blank import.
+var _ = gittransport.DefaultClient
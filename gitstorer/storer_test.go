@@ -0,0 +1,149 @@
+package gitstorer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/knoebber/dotfile/file"
+)
+
+// TestPushPullRoundTrip pushes a commit to a local bare repo from one
+// clone and reads it back from a second, independent clone - the same
+// shape as two machines sharing a dotfiles remote.
+func TestPushPullRoundTrip(t *testing.T) {
+	remoteDir, err := ioutil.TempDir("", "dotfile-gitstorer-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(remoteDir)
+
+	if _, err := git.PlainInit(remoteDir, true); err != nil {
+		t.Fatalf("initializing bare remote: %v", err)
+	}
+
+	pusherCache, err := ioutil.TempDir("", "dotfile-gitstorer-pusher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(pusherCache)
+
+	pusher := &Storage{
+		RemoteURL: remoteDir,
+		CacheDir:  pusherCache,
+		Alias:     "bashrc",
+		Path:      NormalizeTreePath("~/.bashrc"),
+	}
+	if err := pusher.Open(); err != nil {
+		t.Fatalf("opening pusher: %v", err)
+	}
+
+	plaintext := []byte("export PATH=$HOME/bin:$PATH\n")
+	compressed, err := file.Compress(plaintext)
+	if err != nil {
+		t.Fatalf("compressing contents: %v", err)
+	}
+
+	commit := &file.Commit{
+		Hash:    "8f94c7720a648af9cf9dab33e7f297d28b8bf7cd",
+		Message: "Initial commit",
+	}
+
+	if err := pusher.SaveCommit(compressed, commit); err != nil {
+		t.Fatalf("saving commit: %v", err)
+	}
+
+	// The git tree itself should hold the real, readable file content -
+	// not the compressed bytes SaveCommit was handed - so a plain `git
+	// show`/`git diff` on this branch works.
+	pusherHead, err := pusher.repo.Reference(pusher.branchRef(), true)
+	if err != nil {
+		t.Fatalf("resolving pusher branch head: %v", err)
+	}
+	pusherCommit, err := pusher.repo.CommitObject(pusherHead.Hash())
+	if err != nil {
+		t.Fatalf("reading pusher commit: %v", err)
+	}
+	tree, err := pusherCommit.Tree()
+	if err != nil {
+		t.Fatalf("reading pusher tree: %v", err)
+	}
+	treeFile, err := tree.File(pusher.Path)
+	if err != nil {
+		t.Fatalf("reading tree file: %v", err)
+	}
+	treeContents, err := treeFile.Contents()
+	if err != nil {
+		t.Fatalf("reading tree file contents: %v", err)
+	}
+	if treeContents != string(plaintext) {
+		t.Fatalf("tree blob = %q, want plaintext %q", treeContents, plaintext)
+	}
+
+	if err := pusher.Push(); err != nil {
+		t.Fatalf("pushing: %v", err)
+	}
+
+	pullerCache, err := ioutil.TempDir("", "dotfile-gitstorer-puller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(pullerCache)
+
+	puller := &Storage{
+		RemoteURL: remoteDir,
+		CacheDir:  pullerCache,
+		Alias:     "bashrc",
+		Path:      NormalizeTreePath("~/.bashrc"),
+	}
+	if err := puller.Open(); err != nil {
+		t.Fatalf("opening puller: %v", err)
+	}
+
+	hash, message, err := puller.LatestCommit()
+	if err != nil {
+		t.Fatalf("reading latest commit: %v", err)
+	}
+	if hash != commit.Hash {
+		t.Fatalf("got hash %q, want %q", hash, commit.Hash)
+	}
+	if message != commit.Message {
+		t.Fatalf("got message %q, want %q", message, commit.Message)
+	}
+
+	exists, err := puller.HasCommit(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("expected HasCommit to find the pushed commit")
+	}
+
+	got, err := puller.GetRevision(hash)
+	if err != nil {
+		t.Fatalf("reading revision: %v", err)
+	}
+	gotUncompressed, err := file.Uncompress(got)
+	if err != nil {
+		t.Fatalf("uncompressing revision: %v", err)
+	}
+	if gotUncompressed.String() != string(plaintext) {
+		t.Fatalf("got %q, want %q", gotUncompressed, plaintext)
+	}
+}
+
+func TestNormalizeTreePath(t *testing.T) {
+	cases := map[string]string{
+		"~/.bashrc":     ".bashrc",
+		"/etc/hosts":    "etc/hosts",
+		"emacs/init.el": "emacs/init.el",
+	}
+
+	for in, want := range cases {
+		if got := NormalizeTreePath(in); got != want {
+			t.Errorf("NormalizeTreePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
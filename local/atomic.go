@@ -0,0 +1,112 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// renameFunc performs the final rename of atomicWriteFile. It's a var so
+// tests can inject a failure between the temp file write and the rename.
+var renameFunc = os.Rename
+
+// writeCommit durably writes a commit's compressed bytes into the
+// shared object store, deduplicating identical revisions across
+// aliases. alias is accepted to keep this a drop-in replacement for
+// callers that pre-date the shared store; it's no longer part of the
+// blob's path.
+func writeCommit(contents []byte, dir, alias, hash string) error {
+	return writeObject(dir, hash, contents)
+}
+
+// createIfNotExist creates dir if it doesn't already exist.
+func createIfNotExist(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return errors.Wrapf(os.MkdirAll(dir, 0755), "creating directory %s", dir)
+	} else if err != nil {
+		return errors.Wrapf(err, "checking directory %s", dir)
+	}
+
+	return nil
+}
+
+// atomicWriteFile durably writes data to path: it writes to a sibling
+// "path.tmp" file, syncs and closes it, then renames it into place. A
+// crash or power loss can land before or after the rename but never
+// leaves path partially written. When fsyncParentDir is true, the
+// containing directory is also fsynced after the rename so the new
+// directory entry is itself durable - used for the JSON tracking file,
+// where losing the rename itself would lose the entire commit history.
+func atomicWriteFile(path string, data []byte, perm os.FileMode, fsyncParentDir bool) error {
+	dir := filepath.Dir(path)
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return errors.Wrapf(err, "creating temp file %s", tmp)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "writing temp file %s", tmp)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "syncing temp file %s", tmp)
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "closing temp file %s", tmp)
+	}
+
+	if err := renameInPlace(tmp, path); err != nil {
+		return err
+	}
+
+	if !fsyncParentDir {
+		return nil
+	}
+
+	return fsyncDir(dir)
+}
+
+// renameInPlace renames tmp to path. On Windows, renaming onto an
+// existing file fails, so fall back to removing path first.
+func renameInPlace(tmp, path string) error {
+	err := renameFunc(tmp, path)
+	if err == nil {
+		return nil
+	}
+	if runtime.GOOS != "windows" {
+		return errors.Wrapf(err, "renaming %s to %s", tmp, path)
+	}
+
+	if removeErr := os.Remove(path); removeErr != nil && !os.IsNotExist(removeErr) {
+		return errors.Wrapf(err, "renaming %s to %s", tmp, path)
+	}
+
+	if err := renameFunc(tmp, path); err != nil {
+		return errors.Wrapf(err, "renaming %s to %s after removing destination", tmp, path)
+	}
+
+	return nil
+}
+
+// fsyncDir fsyncs a directory so that renames and creates within it are
+// durable, not just the files themselves.
+func fsyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "opening directory %s for fsync", dir)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return errors.Wrapf(err, "syncing directory %s", dir)
+	}
+
+	return nil
+}
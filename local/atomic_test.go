@@ -0,0 +1,42 @@
+package local
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicWriteFile_RenameFailureKeepsOriginal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotfile-atomic-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "emacs.json")
+	original := []byte(`{"path":"~/.emacs.d/init.el"}`)
+
+	if err := ioutil.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := renameFunc
+	renameFunc = func(string, string) error {
+		return errors.New("simulated crash between write and rename")
+	}
+	defer func() { renameFunc = old }()
+
+	err = atomicWriteFile(path, []byte("corrupted"), 0644, false)
+	if err == nil {
+		t.Fatal("expected atomicWriteFile to return the simulated rename error")
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("original file should still be readable: %v", err)
+	}
+
+	if string(got) != string(original) {
+		t.Fatalf("original file was corrupted: got %q want %q", got, original)
+	}
+}
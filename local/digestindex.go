@@ -0,0 +1,122 @@
+package local
+
+import "strings"
+
+// digestIndex is an immutable radix tree mapping an alias-relative
+// logical path - for now just the alias itself, e.g. "emacs" - to the
+// content digest it currently resolves to. SaveCommit keeps it up to
+// date on every commit so a future `dot status` can use the same
+// O(path length) lookup to report cross-alias duplicates; nothing
+// consults it yet. The object store's own existence check (hasObject)
+// is what dedups identical content saved under two different aliases.
+//
+// The tree is immutable: Insert returns a new index sharing every
+// subtree it doesn't touch, so a caller holding an older *digestIndex
+// never sees a later Insert's effects.
+type digestIndex struct {
+	root *radixNode
+}
+
+type radixNode struct {
+	prefix   string
+	digest   string // non-empty when a path terminates exactly at this node
+	children []*radixNode
+}
+
+// newDigestIndex returns an empty index.
+func newDigestIndex() *digestIndex {
+	return &digestIndex{root: &radixNode{}}
+}
+
+// Lookup returns the digest that path currently resolves to, if any.
+func (idx *digestIndex) Lookup(path string) (digest string, ok bool) {
+	return idx.root.lookup(path)
+}
+
+func (n *radixNode) lookup(path string) (string, bool) {
+	for {
+		if path == "" {
+			if n.digest == "" {
+				return "", false
+			}
+			return n.digest, true
+		}
+
+		child := n.matchingChild(path)
+		if child == nil {
+			return "", false
+		}
+
+		path = strings.TrimPrefix(path, child.prefix)
+		n = child
+	}
+}
+
+func (n *radixNode) matchingChild(path string) *radixNode {
+	for _, c := range n.children {
+		if strings.HasPrefix(path, c.prefix) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Insert returns a new index with path set to digest.
+func (idx *digestIndex) Insert(path, digest string) *digestIndex {
+	return &digestIndex{root: idx.root.insert(path, digest)}
+}
+
+func (n *radixNode) insert(path, digest string) *radixNode {
+	if path == "" {
+		clone := *n
+		clone.digest = digest
+		return &clone
+	}
+
+	for i, c := range n.children {
+		common := commonPrefixLen(c.prefix, path)
+		if common == 0 {
+			continue
+		}
+
+		clone := *n
+		clone.children = append([]*radixNode(nil), n.children...)
+
+		if common == len(c.prefix) {
+			clone.children[i] = c.insert(path[common:], digest)
+			return &clone
+		}
+
+		// c.prefix and path diverge partway through; split c into a
+		// shared parent with the old and new suffixes as children.
+		split := &radixNode{
+			prefix: c.prefix[:common],
+			children: []*radixNode{{
+				prefix:   c.prefix[common:],
+				digest:   c.digest,
+				children: c.children,
+			}},
+		}
+
+		if common == len(path) {
+			split.digest = digest
+		} else {
+			split.children = append(split.children, &radixNode{prefix: path[common:], digest: digest})
+		}
+
+		clone.children[i] = split
+		return &clone
+	}
+
+	clone := *n
+	clone.children = append(append([]*radixNode(nil), n.children...), &radixNode{prefix: path, digest: digest})
+	return &clone
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
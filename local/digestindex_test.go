@@ -0,0 +1,38 @@
+package local
+
+import "testing"
+
+func TestDigestIndex(t *testing.T) {
+	idx := newDigestIndex()
+
+	idx = idx.Insert("bashrc", "hash1")
+
+	t.Run("finds an inserted path", func(t *testing.T) {
+		digest, ok := idx.Lookup("bashrc")
+		if !ok || digest != "hash1" {
+			t.Fatalf("got (%q, %v), want (\"hash1\", true)", digest, ok)
+		}
+	})
+
+	t.Run("misses an unknown path", func(t *testing.T) {
+		if _, ok := idx.Lookup("emacs"); ok {
+			t.Fatal("expected a miss for an unindexed path")
+		}
+	})
+
+	t.Run("is immutable across inserts", func(t *testing.T) {
+		updated := idx.Insert("bashrc", "hash2").Insert("emacs", "hash3")
+
+		if digest, _ := idx.Lookup("bashrc"); digest != "hash1" {
+			t.Fatalf("original index changed: got %q, want %q", digest, "hash1")
+		}
+
+		if digest, ok := updated.Lookup("bashrc"); !ok || digest != "hash2" {
+			t.Fatalf("got (%q, %v), want (\"hash2\", true)", digest, ok)
+		}
+
+		if digest, ok := updated.Lookup("emacs"); !ok || digest != "hash3" {
+			t.Fatalf("got (%q, %v), want (\"hash3\", true)", digest, ok)
+		}
+	})
+}
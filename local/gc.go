@@ -0,0 +1,100 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/knoebber/dotfile/file"
+)
+
+// digestIndexCache memoizes digestIndexFor per dir; each index is built
+// from every alias' JSON on a cache miss and then updated by SaveCommit
+// through setDigestIndex. Keyed by dir rather than a single global,
+// since nothing stops a process from operating on more than one dotfile
+// directory.
+var digestIndexCache = make(map[string]*digestIndex)
+
+// digestIndexFor returns the shared digest index for dir, loading it
+// from every alias' JSON tracking file on first use.
+func digestIndexFor(dir string) (*digestIndex, error) {
+	if idx, ok := digestIndexCache[dir]; ok {
+		return idx, nil
+	}
+
+	idx := newDigestIndex()
+	err := walkTrackingData(dir, func(alias string, data *file.TrackingData) error {
+		if data.Revision != "" {
+			idx = idx.Insert(alias, data.Revision)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digestIndexCache[dir] = idx
+	return idx, nil
+}
+
+// setDigestIndex updates the cached digest index for dir, e.g. after a
+// SaveCommit changes what an alias points at.
+func setDigestIndex(dir string, idx *digestIndex) {
+	digestIndexCache[dir] = idx
+}
+
+// GC deletes blobs in dir's shared object store that aren't reachable
+// from any alias' commit history. It's the backing for `dot gc`.
+func GC(dir string) (removed int, err error) {
+	reachable := make(map[string]bool)
+
+	err = walkTrackingData(dir, func(alias string, data *file.TrackingData) error {
+		for _, c := range data.Commits {
+			reachable[c.Hash] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	objectsRoot := filepath.Join(dir, objectsDir)
+	fanouts, err := ioutil.ReadDir(objectsRoot)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "reading %s", objectsRoot)
+	}
+
+	for _, fanout := range fanouts {
+		fanoutDir := filepath.Join(objectsRoot, fanout.Name())
+
+		blobs, err := ioutil.ReadDir(fanoutDir)
+		if err != nil {
+			return removed, errors.Wrapf(err, "reading %s", fanoutDir)
+		}
+
+		for _, blob := range blobs {
+			hash := fanout.Name() + blob.Name()
+			if reachable[hash] {
+				continue
+			}
+
+			if err := os.Remove(filepath.Join(fanoutDir, blob.Name())); err != nil {
+				return removed, errors.Wrapf(err, "removing unreferenced object %s", hash)
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// DefaultDir returns the directory Storage stores its JSON tracking
+// files and shared object store in, given the user's home directory.
+func DefaultDir(home string) string {
+	return filepath.Join(home, ".local", "share", "dotfile")
+}
@@ -0,0 +1,61 @@
+package local
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knoebber/dotfile/file"
+)
+
+func TestGC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotfile-gc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	referenced := "8f94c7720a648af9cf9dab33e7f297d28b8bf7cd"
+	unreferenced := "451de414632e08c0ca3adf7a473b15f37c1b2e60"
+
+	if err := writeObject(dir, referenced, []byte("kept")); err != nil {
+		t.Fatalf("writing referenced object: %v", err)
+	}
+	if err := writeObject(dir, unreferenced, []byte("orphaned")); err != nil {
+		t.Fatalf("writing unreferenced object: %v", err)
+	}
+
+	data := &file.TrackingData{
+		Path:     "~/.bashrc",
+		Revision: referenced,
+		Commits: []file.Commit{
+			{Hash: referenced, Message: "Initial commit", Timestamp: 1},
+		},
+	}
+
+	marshaled, err := json.MarshalIndent(data, "", " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "bashrc.json"), marshaled, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := GC(dir)
+	if err != nil {
+		t.Fatalf("running gc: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected to remove 1 object, removed %d", removed)
+	}
+
+	if !hasObject(dir, referenced) {
+		t.Fatal("referenced object should survive gc")
+	}
+	if hasObject(dir, unreferenced) {
+		t.Fatal("unreferenced object should be deleted by gc")
+	}
+}
@@ -0,0 +1,80 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// objectsDir is the name of the shared, content-addressed blob store
+// that every alias writes its compressed revisions into, instead of
+// each alias keeping its own copy of identical content.
+const objectsDir = "objects"
+
+// objectPath returns a blob's path in the shared object store, using a
+// git-style two-character fanout directory so no single directory ends
+// up with one entry per commit ever made: hash "8f94c77..." becomes
+// "objects/8f/94c77...".
+func objectPath(dir, hash string) string {
+	return filepath.Join(dir, objectsDir, hash[:2], hash[2:])
+}
+
+// hasObject reports whether hash already exists in the shared object
+// store.
+func hasObject(dir, hash string) bool {
+	return exists(objectPath(dir, hash))
+}
+
+// writeObject durably writes a compressed revision to the shared object
+// store, skipping the write when the digest is already present - the
+// same content tracked under two aliases, or copied between files, is
+// stored on disk exactly once.
+func writeObject(dir, hash string, contents []byte) error {
+	if hasObject(dir, hash) {
+		return nil
+	}
+
+	path := objectPath(dir, hash)
+	if err := createIfNotExist(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	return atomicWriteFile(path, contents, 0644, false)
+}
+
+// readObject reads a compressed revision from the shared object store.
+func readObject(dir, hash string) ([]byte, error) {
+	return ioutil.ReadFile(objectPath(dir, hash))
+}
+
+// legacyRevisionPath returns where hash would have been written before
+// aliases shared a single object store: directly under the alias'
+// own directory, e.g. "<dir>/emacs/8f94c77...".
+func legacyRevisionPath(dir, alias, hash string) string {
+	return filepath.Join(dir, alias, hash)
+}
+
+// migrateLegacyRevision reads a revision left behind by a pre-upgrade
+// install at its old per-alias path, copies it into the shared object
+// store, and removes the legacy file. Installs that tracked files
+// before aliases shared a single store still have their history sitting
+// at these paths; without this, GetRevision would fail for every commit
+// made before the upgrade.
+func migrateLegacyRevision(dir, alias, hash string) ([]byte, error) {
+	legacyPath := legacyRevisionPath(dir, alias, hash)
+
+	contents, err := ioutil.ReadFile(legacyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeObject(dir, hash, contents); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(legacyPath); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
@@ -0,0 +1,47 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteObjectDedupsAcrossAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotfile-objectstore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hash := "8f94c7720a648af9cf9dab33e7f297d28b8bf7cd"
+	contents := []byte("compressed bytes shared by two aliases")
+
+	// "bashrc" and "bash_profile" both save the same content.
+	if err := writeObject(dir, hash, contents); err != nil {
+		t.Fatalf("writing object for bashrc: %v", err)
+	}
+	if err := writeObject(dir, hash, contents); err != nil {
+		t.Fatalf("writing object for bash_profile: %v", err)
+	}
+
+	if !hasObject(dir, hash) {
+		t.Fatalf("expected hasObject to report true for %s", hash)
+	}
+
+	blobs, err := ioutil.ReadDir(filepath.Join(dir, objectsDir, hash[:2]))
+	if err != nil {
+		t.Fatalf("reading fanout directory: %v", err)
+	}
+	if len(blobs) != 1 {
+		t.Fatalf("expected exactly one blob on disk, got %d", len(blobs))
+	}
+
+	got, err := readObject(dir, hash)
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if string(got) != string(contents) {
+		t.Fatalf("got %q, want %q", got, contents)
+	}
+}
@@ -1,13 +1,16 @@
 // Package local tracks files by writing to JSON files in the dotfile directory.
 //
 // For every new file that is tracked a new .json file is created.
-// For each commit on a tracked file, a new file is created with the same name as the hash.
+// For each commit on a tracked file, its compressed bytes are written
+// once into a shared, content-addressed object store keyed by hash, so
+// identical content tracked under two aliases is only stored on disk a
+// single time.
 //
 // Example: ~/.emacs.d/init.el is added with alias "emacs".
 // Supposing Storage.dir is ~/.config/dotfile, then the following files are created:
 //
 // ~/.config/dotfile/emacs.json
-// ~/.config/dotfile/emacs/8f94c7720a648af9cf9dab33e7f297d28b8bf7cd
+// ~/.config/dotfile/objects/8f/94c7720a648af9cf9dab33e7f297d28b8bf7cd
 //
 // The emacs.json file would look something like this:
 // {
@@ -94,7 +97,9 @@ func (s *Storage) Close() error {
 	}
 
 	// Example: ~/.local/share/dotfile/bash_profile.json
-	if err := ioutil.WriteFile(s.jsonPath, bytes, 0644); err != nil {
+	// The JSON holds the file's entire commit history, so it's written
+	// durably: a crash here should never truncate it.
+	if err := atomicWriteFile(s.jsonPath, bytes, 0644, true); err != nil {
 		return errors.Wrap(err, "saving tracking data")
 	}
 
@@ -112,12 +117,19 @@ func (s *Storage) HasCommit(hash string) (exists bool, err error) {
 	return
 }
 
-// GetRevision returns the files state at hash.
+// GetRevision returns the files state at hash. Installs that tracked
+// files before the shared object store existed may still have this
+// revision sitting at its old per-alias path; GetRevision falls back to
+// that path and migrates it into the shared store so it's only paid
+// once.
 func (s *Storage) GetRevision(hash string) ([]byte, error) {
-	revisionPath := filepath.Join(s.dir, s.Alias, hash)
+	bytes, err := readObject(s.dir, hash)
+	if err == nil {
+		return bytes, nil
+	}
 
-	bytes, err := ioutil.ReadFile(revisionPath)
-	if err != nil {
+	bytes, migrateErr := migrateLegacyRevision(s.dir, s.Alias, hash)
+	if migrateErr != nil {
 		return nil, errors.Wrapf(err, "reading revision %#v", hash)
 	}
 
@@ -137,19 +149,27 @@ func (s *Storage) GetContents() ([]byte, error) {
 // SaveCommit saves a commit to the file system.
 // Creates a new directory when its the first commit.
 // Updates the file's revision field to point to the new hash.
+// Writes the commit's bytes to the shared object store; the store's own
+// existence check is what dedups identical content saved under two
+// different aliases.
 func (s *Storage) SaveCommit(buff *bytes.Buffer, c *file.Commit) error {
 	s.FileData.Commits = append(s.FileData.Commits, *c)
+
 	if err := writeCommit(buff.Bytes(), s.dir, s.Alias, c.Hash); err != nil {
 		return err
 	}
 
+	if idx, err := digestIndexFor(s.dir); err == nil {
+		setDigestIndex(s.dir, idx.Insert(s.Alias, c.Hash))
+	}
+
 	s.FileData.Revision = c.Hash
 	return nil
 }
 
 // Revert overwrites a file at path with contents.
 func (s *Storage) Revert(buff *bytes.Buffer, hash string) error {
-	err := ioutil.WriteFile(s.GetPath(), buff.Bytes(), 0644)
+	err := atomicWriteFile(s.GetPath(), buff.Bytes(), 0644, false)
 	if err != nil {
 		return errors.Wrapf(err, "reverting file %q", s.GetPath())
 	}
@@ -0,0 +1,62 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knoebber/dotfile/file"
+)
+
+// TestStorage_GetRevision_MigratesLegacyPath covers installs that
+// tracked files before aliases shared a single object store: their
+// history still sits at the old "<dir>/<alias>/<hash>" path.
+func TestStorage_GetRevision_MigratesLegacyPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotfile-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hash := "8f94c7720a648af9cf9dab33e7f297d28b8bf7cd"
+	contents := []byte("compressed bashrc bytes")
+
+	legacyDir := filepath.Join(dir, "bashrc")
+	if err := os.MkdirAll(legacyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(legacyDir, hash), contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Storage{
+		Alias:    "bashrc",
+		FileData: &file.TrackingData{Path: "~/.bashrc", Revision: hash},
+		dir:      dir,
+	}
+
+	got, err := s.GetRevision(hash)
+	if err != nil {
+		t.Fatalf("reading revision: %v", err)
+	}
+	if string(got) != string(contents) {
+		t.Fatalf("got %q, want %q", got, contents)
+	}
+
+	if !hasObject(dir, hash) {
+		t.Fatal("expected revision to be migrated into the shared object store")
+	}
+	if exists(filepath.Join(legacyDir, hash)) {
+		t.Fatal("expected legacy revision file to be removed after migration")
+	}
+
+	// A second read should come straight from the shared store.
+	got, err = s.GetRevision(hash)
+	if err != nil {
+		t.Fatalf("re-reading revision: %v", err)
+	}
+	if string(got) != string(contents) {
+		t.Fatalf("got %q, want %q", got, contents)
+	}
+}
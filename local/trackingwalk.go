@@ -0,0 +1,43 @@
+package local
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/knoebber/dotfile/file"
+)
+
+// walkTrackingData calls fn with the alias and parsed tracking data for
+// every <alias>.json file in dir. It's the shared read path for anything
+// that needs to see every tracked file at once, such as rebuilding the
+// digest index or running gc.
+func walkTrackingData(dir string, fn func(alias string, data *file.TrackingData) error) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return errors.Wrap(err, "listing tracked files")
+	}
+
+	for _, jsonPath := range matches {
+		alias := strings.TrimSuffix(filepath.Base(jsonPath), ".json")
+
+		contents, err := ioutil.ReadFile(jsonPath)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", jsonPath)
+		}
+
+		data := new(file.TrackingData)
+		if err := json.Unmarshal(contents, data); err != nil {
+			return errors.Wrapf(err, "unmarshaling %s", jsonPath)
+		}
+
+		if err := fn(alias, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}